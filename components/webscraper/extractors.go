@@ -0,0 +1,294 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly"
+	"github.com/ledongthuc/pdf"
+)
+
+// Extracted is the normalized result of running a ContentExtractor over a
+// response body: plain text/markdown suitable for embedding, plus whatever
+// metadata is worth recording alongside it.
+type Extracted struct {
+	// Text is the normalized, human-readable content (HTML boilerplate
+	// stripped, binary formats converted to text).
+	Text string
+	// SidecarExt is the file extension ("txt" or "md") to use when writing
+	// Text to GCS as a sidecar of the raw document.
+	SidecarExt string
+	// PageCount is set by extractors that can determine it (e.g. PDF); 0
+	// otherwise.
+	PageCount int
+}
+
+// ContentExtractor converts a scraped response into normalized text. Each
+// registered extractor is tried in order; the first whose Matches returns
+// true handles the response.
+type ContentExtractor interface {
+	// Matches reports whether this extractor handles the given Content-Type
+	// and/or URL.
+	Matches(contentType, url string) bool
+	// Extract converts resp's body into Extracted content.
+	Extract(ctx context.Context, resp *colly.Response) (Extracted, error)
+}
+
+// extractorRegistry holds the ordered list of extractors consulted for each
+// response, and the allow-list (if any) restricting which content types are
+// saved at all.
+type extractorRegistry struct {
+	extractors          []ContentExtractor
+	allowedContentTypes []string
+}
+
+// newExtractorRegistry builds the default registry: HTML, PDF, Markdown, CSV,
+// DOCX/PPTX, plain text, and images, in that order, so a .md or .csv URL
+// served as text/plain goes to the Markdown or CSV extractor. The image
+// extractor is an OCR hook point (see imageExtractor): it matches image
+// content types, but its Extract always returns an error, so images are
+// saved raw with no sidecar.
+func newExtractorRegistry(allowedContentTypes []string) *extractorRegistry {
+	return &extractorRegistry{
+		allowedContentTypes: allowedContentTypes,
+		extractors: []ContentExtractor{
+			htmlExtractor{},
+			pdfExtractor{},
+			markdownExtractor{},
+			csvExtractor{},
+			officeXMLExtractor{},
+			plainTextExtractor{},
+			imageExtractor{},
+		},
+	}
+}
+
+// find returns the first extractor that matches contentType/url, or nil if
+// the content type isn't one we know how to handle, or isn't in the
+// allow-list.
+func (reg *extractorRegistry) find(contentType, url string) ContentExtractor {
+	if len(reg.allowedContentTypes) > 0 && !containsContentType(reg.allowedContentTypes, contentType) {
+		return nil
+	}
+	for _, extractor := range reg.extractors {
+		if extractor.Matches(contentType, url) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+func containsContentType(allowed []string, contentType string) bool {
+	for _, a := range allowed {
+		if strings.Contains(contentType, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlExtractor strips tags and script/style content from HTML, leaving
+// plain-text body copy suitable for RAG ingestion.
+type htmlExtractor struct{}
+
+func (htmlExtractor) Matches(contentType, url string) bool {
+	return strings.Contains(contentType, "text/html")
+}
+
+var (
+	scriptOrStyleRe = regexp.MustCompile(`(?is)<(script|style|nav|footer|header)\b[^>]*>.*?</\s*\w+\s*>`)
+	tagRe           = regexp.MustCompile(`(?s)<[^>]+>`)
+	whitespaceRe    = regexp.MustCompile(`[ \t]*\n[ \t]*\n+`)
+)
+
+func (htmlExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	body := scriptOrStyleRe.ReplaceAll(resp.Body, []byte(" "))
+	body = tagRe.ReplaceAll(body, []byte("\n"))
+	text := whitespaceRe.ReplaceAllString(strings.TrimSpace(string(body)), "\n\n")
+	return Extracted{Text: text, SidecarExt: "txt"}, nil
+}
+
+// pdfExtractor extracts page text and a page count using a pure-Go PDF
+// parser, avoiding a cgo dependency in the scraper image.
+type pdfExtractor struct{}
+
+func (pdfExtractor) Matches(contentType, url string) bool {
+	return strings.Contains(contentType, "application/pdf")
+}
+
+func (pdfExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(resp.Body), int64(len(resp.Body)))
+	if err != nil {
+		return Extracted{}, fmt.Errorf("error opening PDF: %v", err)
+	}
+
+	var buf bytes.Buffer
+	numPages := reader.NumPage()
+	for i := 1; i <= numPages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		pageText, err := page.GetPlainText(nil)
+		if err != nil {
+			continue
+		}
+		buf.WriteString(pageText)
+		buf.WriteString("\n\n")
+	}
+
+	return Extracted{Text: buf.String(), SidecarExt: "txt", PageCount: numPages}, nil
+}
+
+// plainTextExtractor passes plain-text responses through unchanged.
+type plainTextExtractor struct{}
+
+func (plainTextExtractor) Matches(contentType, url string) bool {
+	return strings.Contains(contentType, "text/plain")
+}
+
+func (plainTextExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	return Extracted{Text: string(resp.Body), SidecarExt: "txt"}, nil
+}
+
+// markdownExtractor passes Markdown responses through unchanged; Markdown is
+// already a reasonable RAG ingestion format.
+type markdownExtractor struct{}
+
+func (markdownExtractor) Matches(contentType, url string) bool {
+	return strings.Contains(contentType, "text/markdown") || strings.HasSuffix(strings.ToLower(url), ".md")
+}
+
+func (markdownExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	return Extracted{Text: string(resp.Body), SidecarExt: "md"}, nil
+}
+
+// csvExtractor renders CSV rows as pipe-separated lines, which reads more
+// naturally as RAG context than raw comma-separated text.
+type csvExtractor struct{}
+
+func (csvExtractor) Matches(contentType, url string) bool {
+	return strings.Contains(contentType, "text/csv") || strings.HasSuffix(strings.ToLower(url), ".csv")
+}
+
+func (csvExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	reader := csv.NewReader(bytes.NewReader(resp.Body))
+	reader.FieldsPerRecord = -1
+
+	var buf bytes.Buffer
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Extracted{}, fmt.Errorf("error parsing CSV: %v", err)
+		}
+		buf.WriteString(strings.Join(record, " | "))
+		buf.WriteString("\n")
+	}
+
+	return Extracted{Text: buf.String(), SidecarExt: "txt"}, nil
+}
+
+// officeXMLExtractor handles the zipped-XML Office formats (DOCX, PPTX) by
+// unzipping the package and concatenating the text runs from its XML parts.
+type officeXMLExtractor struct{}
+
+func (officeXMLExtractor) Matches(contentType, url string) bool {
+	if strings.Contains(contentType, "officedocument.wordprocessingml") ||
+		strings.Contains(contentType, "officedocument.presentationml") {
+		return true
+	}
+	lower := strings.ToLower(url)
+	return strings.HasSuffix(lower, ".docx") || strings.HasSuffix(lower, ".pptx")
+}
+
+// officeXMLPart lists, per format, which zip entries hold document text.
+var officeXMLPart = map[string]string{
+	".docx": "word/document.xml",
+	".pptx": "ppt/slides/slide",
+}
+
+func (officeXMLExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	zr, err := zip.NewReader(bytes.NewReader(resp.Body), int64(len(resp.Body)))
+	if err != nil {
+		return Extracted{}, fmt.Errorf("error opening Office document as zip: %v", err)
+	}
+
+	var buf bytes.Buffer
+	for _, f := range zr.File {
+		if f.Name != officeXMLPart[".docx"] && !strings.HasPrefix(f.Name, officeXMLPart[".pptx"]) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		text, err := extractXMLText(rc)
+		rc.Close()
+		if err != nil {
+			continue
+		}
+		buf.WriteString(text)
+		buf.WriteString("\n\n")
+	}
+
+	return Extracted{Text: strings.TrimSpace(buf.String()), SidecarExt: "txt"}, nil
+}
+
+// extractXMLText concatenates the character data of every XML element,
+// which is sufficient to recover readable text from Office Open XML parts
+// without a full OOXML schema.
+func extractXMLText(r io.Reader) (string, error) {
+	decoder := xml.NewDecoder(r)
+	var buf bytes.Buffer
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if chars, ok := tok.(xml.CharData); ok {
+			buf.Write(chars)
+			buf.WriteString(" ")
+		}
+	}
+	return buf.String(), nil
+}
+
+// imageExtractor is a hook point for OCR. It matches known image content
+// types but performs no extraction today; wiring in an OCR engine (e.g. a
+// Cloud Vision API call) only requires implementing Extract below.
+type imageExtractor struct{}
+
+func (imageExtractor) Matches(contentType, url string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+func (imageExtractor) Extract(ctx context.Context, resp *colly.Response) (Extracted, error) {
+	return Extracted{}, fmt.Errorf("OCR extraction not yet implemented for %s", resp.Request.URL)
+}
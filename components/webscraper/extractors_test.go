@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/gocolly/colly"
+	"github.com/stretchr/testify/assert"
+)
+
+func responseWithBody(body string) *colly.Response {
+	return &colly.Response{Body: []byte(body)}
+}
+
+func TestHTMLExtractorStripsTagsAndScripts(t *testing.T) {
+	body := `<html><head><script>evil()</script></head><body><h1>Title</h1><p>Hello world</p></body></html>`
+	extracted, err := htmlExtractor{}.Extract(context.Background(), responseWithBody(body))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "txt", extracted.SidecarExt)
+	assert.Contains(t, extracted.Text, "Title")
+	assert.Contains(t, extracted.Text, "Hello world")
+	assert.NotContains(t, extracted.Text, "evil()")
+}
+
+func TestCSVExtractorJoinsFieldsWithPipes(t *testing.T) {
+	extracted, err := csvExtractor{}.Extract(context.Background(), responseWithBody("a,b,c\n1,2,3\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a | b | c\n1 | 2 | 3\n", extracted.Text)
+}
+
+func TestMarkdownExtractorMatchesByContentTypeOrExtension(t *testing.T) {
+	e := markdownExtractor{}
+	assert.True(t, e.Matches("text/markdown", "https://example.com/page"))
+	assert.True(t, e.Matches("text/plain", "https://example.com/readme.MD"))
+	assert.False(t, e.Matches("text/html", "https://example.com/page"))
+}
+
+func TestRegistryFindRespectsAllowList(t *testing.T) {
+	reg := newExtractorRegistry([]string{"text/html"})
+
+	assert.NotNil(t, reg.find("text/html", "https://example.com/"))
+	assert.Nil(t, reg.find("application/pdf", "https://example.com/doc.pdf"), "application/pdf is not in the allow-list")
+}
+
+func TestRegistryFindReturnsNilForUnknownContentType(t *testing.T) {
+	reg := newExtractorRegistry(nil)
+	assert.Nil(t, reg.find("application/octet-stream", "https://example.com/file.bin"))
+}
+
+func TestImageExtractorReturnsNotImplementedError(t *testing.T) {
+	u, err := url.Parse("https://example.com/photo.png")
+	assert.NoError(t, err)
+
+	_, err = imageExtractor{}.Extract(context.Background(), &colly.Response{Request: &colly.Request{URL: u}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Render modes selectable via JobInput.RenderMode.
+const (
+	renderModeHTTP     = "http"
+	renderModeHeadless = "headless"
+	renderModeAuto     = "auto"
+)
+
+// minAnchorsForHTTP is the number of anchor tags below which "auto" mode
+// treats an HTTP-fetched page as a probable JS-rendered shell and retries
+// with the headless fetcher.
+const minAnchorsForHTTP = 3
+
+var anchorTagRe = regexp.MustCompile(`(?i)<a\s+[^>]*href=`)
+var emptyRootDivRe = regexp.MustCompile(`(?i)<div[^>]*\bid\s*=\s*["']root["'][^>]*>\s*</div>`)
+
+// Rendered is the result of fetching and, where applicable, rendering a page.
+type Rendered struct {
+	HTML       string
+	StatusCode int
+	// Screenshot holds a PNG screenshot of the rendered page, set only by
+	// the headless fetcher.
+	Screenshot []byte
+}
+
+// Fetcher retrieves a page's content. httpFetcher is the "http"
+// implementation, wrapping the response colly already fetched; headlessFetcher
+// is the "headless" implementation, used directly for "headless" mode and as
+// a fallback retry in "auto" mode when the HTTP-fetched HTML looks like an
+// unrendered SPA shell.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (Rendered, error)
+}
+
+// httpFetcher implements Fetcher by wrapping HTML colly already fetched over
+// plain HTTP, so "http" mode is dispatched through the same Fetcher
+// interface as "headless" mode rather than being special-cased.
+type httpFetcher struct {
+	html string
+}
+
+func (f httpFetcher) Fetch(ctx context.Context, url string) (Rendered, error) {
+	return Rendered{HTML: f.html, StatusCode: 200}, nil
+}
+
+// looksLikeEmptyShell is a cheap heuristic for "this page needed JavaScript
+// to render": very few links, or an empty SPA root div.
+func looksLikeEmptyShell(html string) bool {
+	if len(anchorTagRe.FindAllString(html, minAnchorsForHTTP+1)) < minAnchorsForHTTP {
+		return true
+	}
+	return emptyRootDivRe.MatchString(html)
+}
+
+// normalizeRenderMode defaults an empty RenderMode to the previous
+// HTTP-only behavior.
+func normalizeRenderMode(mode string) string {
+	if mode == "" {
+		return renderModeHTTP
+	}
+	return mode
+}
+
+// headlessFetcher renders pages with a real, headless Chrome instance via
+// chromedp, for sites that return an empty shell without executing
+// JavaScript.
+type headlessFetcher struct {
+	waitSelector   string
+	timeout        time.Duration
+	allocatorCtx   context.Context
+	allocatorClose context.CancelFunc
+
+	// sem bounds the number of concurrent browser tabs so a crawl with high
+	// Parallelism doesn't spawn unbounded Chrome processes/tabs.
+	sem chan struct{}
+}
+
+// newHeadlessFetcher starts a shared headless Chrome allocator capped at
+// maxConcurrent simultaneous tabs, used for the lifetime of the crawl.
+func newHeadlessFetcher(waitSelector string, timeout time.Duration, maxConcurrent int) *headlessFetcher {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 2
+	}
+	allocatorCtx, cancel := chromedp.NewExecAllocator(context.Background(), chromedp.DefaultExecAllocatorOptions[:]...)
+	return &headlessFetcher{
+		waitSelector:   waitSelector,
+		timeout:        timeout,
+		allocatorCtx:   allocatorCtx,
+		allocatorClose: cancel,
+		sem:            make(chan struct{}, maxConcurrent),
+	}
+}
+
+func (f *headlessFetcher) Close() {
+	f.allocatorClose()
+}
+
+// headlessAsFetcher converts a possibly-nil *headlessFetcher to a Fetcher.
+// A plain type assertion would store a non-nil interface around a nil
+// pointer, breaking the `headless == nil` checks elsewhere in this file.
+func headlessAsFetcher(h *headlessFetcher) Fetcher {
+	if h == nil {
+		return nil
+	}
+	return h
+}
+
+func (f *headlessFetcher) Fetch(ctx context.Context, url string) (Rendered, error) {
+	f.sem <- struct{}{}
+	defer func() { <-f.sem }()
+
+	tabCtx, cancelTab := chromedp.NewContext(f.allocatorCtx)
+	defer cancelTab()
+
+	timeout := f.timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, timeout)
+	defer cancelTimeout()
+
+	var html string
+	var screenshot []byte
+	actions := []chromedp.Action{chromedp.Navigate(url)}
+	if f.waitSelector != "" {
+		actions = append(actions, chromedp.WaitVisible(f.waitSelector, chromedp.ByQuery))
+	} else {
+		actions = append(actions, chromedp.WaitReady("body", chromedp.ByQuery))
+	}
+	actions = append(actions,
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+		chromedp.CaptureScreenshot(&screenshot),
+	)
+
+	if err := chromedp.Run(tabCtx, actions...); err != nil {
+		return Rendered{}, fmt.Errorf("error rendering %s: %v", url, err)
+	}
+
+	return Rendered{HTML: html, StatusCode: 200, Screenshot: screenshot}, nil
+}
+
+// renderHTMLIfNeeded applies JobInput.RenderMode to an HTML response already
+// fetched over plain HTTP by colly: "http" leaves it unchanged, "headless"
+// always re-renders it, and "auto" re-renders only if it looks like an
+// unrendered SPA shell. Returns the HTML to use and a screenshot, non-nil
+// only when headless rendering actually ran.
+func renderHTMLIfNeeded(ctx context.Context, url, httpHTML, renderMode string, headless Fetcher) (string, []byte) {
+	switch normalizeRenderMode(renderMode) {
+	case renderModeHeadless:
+		if headless == nil {
+			return httpHTML, nil
+		}
+		rendered, err := headless.Fetch(ctx, url)
+		if err != nil {
+			log.Printf("Headless render of %s failed, falling back to HTTP response: %v", url, err)
+			return httpHTML, nil
+		}
+		return rendered.HTML, rendered.Screenshot
+	case renderModeAuto:
+		if headless == nil {
+			return httpHTML, nil
+		}
+		return fetchWithAutoRender(ctx, url, httpHTML, headless)
+	default:
+		rendered, _ := (httpFetcher{html: httpHTML}).Fetch(ctx, url)
+		return rendered.HTML, nil
+	}
+}
+
+// fetchWithAutoRender runs httpHTML through the "auto" heuristic, retrying
+// with headless rendering if the page looks like an unrendered SPA shell.
+// It returns the (possibly re-rendered) HTML and a screenshot, which is nil
+// unless headless rendering was used.
+func fetchWithAutoRender(ctx context.Context, url, httpHTML string, headless Fetcher) (string, []byte) {
+	if !looksLikeEmptyShell(httpHTML) {
+		return httpHTML, nil
+	}
+	log.Printf("%s looks like an unrendered SPA shell, retrying with headless fetcher", url)
+	rendered, err := headless.Fetch(ctx, url)
+	if err != nil {
+		log.Printf("Headless retry for %s failed, keeping original HTML: %v", url, err)
+		return httpHTML, nil
+	}
+	return rendered.HTML, rendered.Screenshot
+}
+
+// screenshotFilename derives the sibling screenshot object name for a saved
+// HTML document.
+func screenshotFilename(htmlFilename string) string {
+	return strings.TrimSuffix(htmlFilename, ".html") + ".png"
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLooksLikeEmptyShell(t *testing.T) {
+	assert.True(t, looksLikeEmptyShell(`<html><body><div id="root"></div></body></html>`))
+	assert.True(t, looksLikeEmptyShell(`<html><body>just one <a href="/x">link</a></body></html>`))
+
+	full := `<html><body>
+		<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/d">d</a>
+	</body></html>`
+	assert.False(t, looksLikeEmptyShell(full))
+}
+
+func TestNormalizeRenderMode(t *testing.T) {
+	assert.Equal(t, renderModeHTTP, normalizeRenderMode(""))
+	assert.Equal(t, renderModeHeadless, normalizeRenderMode(renderModeHeadless))
+}
+
+func TestScreenshotFilename(t *testing.T) {
+	assert.Equal(t, "page.png", screenshotFilename("page.html"))
+}
+
+func TestHTTPFetcherReturnsWrappedHTML(t *testing.T) {
+	rendered, err := httpFetcher{html: "<html>hi</html>"}.Fetch(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "<html>hi</html>", rendered.HTML)
+	assert.Equal(t, 200, rendered.StatusCode)
+}
+
+func TestHeadlessAsFetcherHandlesNilWithoutTypedNilTrap(t *testing.T) {
+	var h *headlessFetcher
+	fetcher := headlessAsFetcher(h)
+	assert.Nil(t, fetcher, "a nil *headlessFetcher must convert to a nil Fetcher, not a non-nil interface wrapping a nil pointer")
+}
+
+func TestRenderHTMLIfNeededDefaultsToHTTPFetcher(t *testing.T) {
+	html, screenshot := renderHTMLIfNeeded(context.Background(), "https://example.com", "<html>original</html>", renderModeHTTP, nil)
+	assert.Equal(t, "<html>original</html>", html)
+	assert.Nil(t, screenshot)
+}
+
+func TestRenderHTMLIfNeededHeadlessWithNilFetcherFallsBackToHTTP(t *testing.T) {
+	html, screenshot := renderHTMLIfNeeded(context.Background(), "https://example.com", "<html>original</html>", renderModeHeadless, nil)
+	assert.Equal(t, "<html>original</html>", html)
+	assert.Nil(t, screenshot)
+}
@@ -19,10 +19,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"cloud.google.com/go/storage"
@@ -37,6 +42,19 @@ type ScrapedDocument struct {
 	URL         string `json:"url"`
 	GCSPath     string `json:"gcs_path"`
 	ContentType string `json:"content_type"`
+	// ExtractedGCSPath is the GCS path of the normalized .txt/.md sidecar
+	// produced by the matching ContentExtractor, if any.
+	ExtractedGCSPath string `json:"extracted_gcs_path,omitempty"`
+
+	// ETag and LastModified are recorded from the response so later runs can
+	// send conditional-request headers and skip unchanged pages.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// SHA256 is the digest of the raw body, used to detect byte-identical
+	// content even when a site doesn't support conditional requests.
+	SHA256 string `json:"sha256,omitempty"`
+	// FetchedAt is when this document was last (re)scraped, RFC 3339.
+	FetchedAt string `json:"fetched_at,omitempty"`
 }
 
 // JobInput represents the input data for a scraping job
@@ -44,6 +62,59 @@ type JobInput struct {
 	URL        string `json:"url"`
 	EngineName string `json:"query_engine_name"`
 	DepthLimit string `json:"depth_limit"`
+
+	// RateLimitPerHost caps requests per second to any single host. 0 means
+	// no explicit cap (colly's default parallelism still applies).
+	RateLimitPerHost float64 `json:"rate_limit_per_host"`
+	// Parallelism caps the number of concurrent requests per host.
+	Parallelism int `json:"parallelism"`
+	// RandomDelayMs adds a random delay, in milliseconds, between requests to
+	// the same host, on top of RateLimitPerHost.
+	RandomDelayMs int `json:"random_delay_ms"`
+	// RespectRobotsTxt enables robots.txt parsing (Disallow/Allow/Crawl-delay)
+	// for the configured User-Agent. Defaults to true when unset.
+	RespectRobotsTxt *bool `json:"respect_robots_txt"`
+
+	// AllowedContentTypes restricts which Content-Type substrings are saved.
+	// Empty means every content type with a registered ContentExtractor is
+	// saved, matching the previous HTML/PDF-only behavior plus the new
+	// formats.
+	AllowedContentTypes []string `json:"allowed_content_types"`
+
+	// SeedMode selects how the crawl is seeded: "crawl" (default, follow
+	// links from URL), "sitemap" (seed from sitemap.xml, no link-following),
+	// "feed" (seed from an RSS/Atom feed), or "hybrid" (seed from sitemap.xml
+	// but still follow links up to DepthLimit).
+	SeedMode string `json:"seed_mode"`
+	// SitemapURL overrides the sitemap location for "sitemap"/"hybrid" mode.
+	// Defaults to the site's robots.txt "Sitemap:" directive, or
+	// "{URL}/sitemap.xml" if neither is set.
+	SitemapURL string `json:"sitemap_url"`
+	// FeedURL is the RSS/Atom feed to seed from in "feed" mode.
+	FeedURL string `json:"feed_url"`
+
+	// FullRefresh opts into the previous destructive behavior of clearing
+	// the engine's bucket before crawling. When false (the default), the
+	// bucket is left in place and the crawl manifest is used to do an
+	// incremental re-crawl instead.
+	FullRefresh bool `json:"full_refresh"`
+
+	// RenderMode selects how HTML pages are fetched: "http" (default, plain
+	// GET via colly), "headless" (always render with headless Chrome), or
+	// "auto" (use the HTTP response unless it looks like an unrendered SPA
+	// shell, then retry with headless rendering).
+	RenderMode string `json:"render_mode"`
+	// RenderWaitSelector is a CSS selector the headless fetcher waits to
+	// become visible before capturing the page. Without it, the fetcher
+	// waits only until the <body> element is ready; it does not wait for
+	// network activity to settle.
+	RenderWaitSelector string `json:"render_wait_selector"`
+	// RenderTimeoutSeconds caps how long a single headless render may take.
+	// Defaults to 30s when unset.
+	RenderTimeoutSeconds int `json:"render_timeout_seconds"`
+	// MaxConcurrentBrowsers caps the number of simultaneous headless Chrome
+	// tabs, to protect the job's memory budget. Defaults to 2 when unset.
+	MaxConcurrentBrowsers int `json:"max_concurrent_browsers"`
 }
 
 // Add global storage client
@@ -78,20 +149,78 @@ func main() {
 	// Generate and initialize bucket
 	bucketName := generateAndInitializeBucket(ctx, projectID, jobInput, docRef)
 
+	// Load (or create) the persistent visit queue, resuming any prior
+	// snapshot so a preempted job restarted with the same JOB_ID continues
+	// where it left off instead of re-crawling from the seed URL.
+	visitQueue, err := newGCSVisitQueue(ctx, bucketName)
+	if err != nil {
+		updateJobError(ctx, docRef, fmt.Errorf("failed to load visit queue: %v", err))
+		log.Print(err)
+		os.Exit(1)
+	}
+
+	// Load the previous crawl manifest (if any) to support incremental
+	// re-crawls of the same engine.
+	manifest, err := loadCrawlManifest(ctx, bucketName)
+	if err != nil {
+		updateJobError(ctx, docRef, fmt.Errorf("failed to load crawl manifest: %v", err))
+		log.Print(err)
+		os.Exit(1)
+	}
+
+	// Start the headless renderer if this job might need it; it's shared
+	// across the whole crawl so concurrent tabs stay bounded.
+	var headless *headlessFetcher
+	if mode := normalizeRenderMode(jobInput.RenderMode); mode == renderModeHeadless || mode == renderModeAuto {
+		headless = newHeadlessFetcher(
+			jobInput.RenderWaitSelector,
+			time.Duration(jobInput.RenderTimeoutSeconds)*time.Second,
+			jobInput.MaxConcurrentBrowsers,
+		)
+		defer headless.Close()
+	}
+
 	// Set up Colly collector
-	collector, scrapedDocs := setupCollector(ctx, jobInput, bucketName, docRef)
+	collector, scrapedDocs, skippedURLs := setupCollector(ctx, jobInput, bucketName, docRef, visitQueue, manifest, headlessAsFetcher(headless))
 
-	// Start scraping
-	err = collector.Visit(jobInput.URL)
+	// Re-seed the collector with any URLs left over from a previous,
+	// interrupted run of this job.
+	resumeFromSnapshot(collector, visitQueue)
+
+	// Start scraping. In "sitemap"/"feed"/"hybrid" mode, seed directly from
+	// the sitemap or feed instead of (or in addition to) following links
+	// from the seed URL.
+	seeds, err := seedURLs(jobInput)
 	if err != nil {
-		log.Printf("Error starting scrape: %v", err)
+		log.Printf("Error resolving seed URLs: %v", err)
+	}
+	if len(seeds) > 0 {
+		log.Printf("Seeding crawl with %d URLs from %s mode", len(seeds), normalizeSeedMode(jobInput.SeedMode))
+		for _, seed := range seeds {
+			if err := collector.Visit(seed); err != nil {
+				log.Printf("Error visiting seed %s: %v", seed, err)
+			}
+		}
+	} else if followsLinks(jobInput) {
+		if err := collector.Visit(jobInput.URL); err != nil {
+			log.Printf("Error starting scrape: %v", err)
+		}
 	}
 	collector.Wait()
 
-	log.Printf("Scraping complete. Found %d documents", len(*scrapedDocs))
+	if err := visitQueue.Snapshot(ctx); err != nil {
+		log.Printf("Error flushing final visit queue snapshot: %v", err)
+	}
+
+	if err := manifest.save(ctx); err != nil {
+		log.Printf("Error saving crawl manifest: %v", err)
+	}
+	logManifestSummary(manifest)
+
+	log.Printf("Scraping complete. Found %d documents, skipped %d disallowed URLs", len(*scrapedDocs), len(*skippedURLs))
 
 	// Save results and update job status
-	saveResults(ctx, firestoreClient, docRef, scrapedDocs)
+	saveResults(ctx, firestoreClient, docRef, scrapedDocs, skippedURLs, manifest)
 }
 
 func configureLogger() {
@@ -191,7 +320,7 @@ func generateAndInitializeBucket(ctx context.Context, projectID string, jobInput
 	log.Printf("Using bucket: %s", bucketName)
 
 	// Initialize bucket
-	if err := initializeBucket(ctx, projectID, bucketName); err != nil {
+	if err := initializeBucket(ctx, projectID, bucketName, jobInput.FullRefresh); err != nil {
 		updateJobError(ctx, docRef, fmt.Errorf("failed to initialize bucket: %v", err))
 		log.Print(err)
 	}
@@ -199,8 +328,41 @@ func generateAndInitializeBucket(ctx context.Context, projectID string, jobInput
 	return bucketName
 }
 
-func setupCollector(ctx context.Context, jobInput JobInput, bucketName string, docRef *firestore.DocumentRef) (*colly.Collector, *[]ScrapedDocument) {
+// resumeFromSnapshot re-queues every URL left pending in visitQueue from a
+// prior, interrupted run. Colly's own frontier is in-memory only and does
+// not survive a restart, so this is what actually makes a restart resume
+// rather than re-crawl from the seed URL.
+func resumeFromSnapshot(collector *colly.Collector, visitQueue VisitQueue) {
+	resumed := 0
+	for {
+		url, _, ok := visitQueue.Dequeue()
+		if !ok {
+			break
+		}
+		if err := collector.Visit(url); err != nil {
+			log.Printf("Error resuming visit to %s: %v", url, err)
+			// Visit failed before OnRequest/OnResponse/OnError could mark the
+			// URL visited (e.g. it's outside AllowedDomains or past
+			// DepthLimit). Mark it visited here too, otherwise it stays
+			// pending forever and gets re-dequeued on every future resume.
+			if markErr := visitQueue.MarkVisited(url); markErr != nil {
+				log.Printf("Error marking %s visited after resume failure: %v", url, markErr)
+			}
+			continue
+		}
+		resumed++
+	}
+	if resumed > 0 {
+		log.Printf("Resumed %d pending URLs from previous run", resumed)
+	}
+}
+
+func setupCollector(ctx context.Context, jobInput JobInput, bucketName string, docRef *firestore.DocumentRef, visitQueue VisitQueue, manifest *crawlManifest, headless Fetcher) (*colly.Collector, *[]ScrapedDocument, *[]string) {
 	var scrapedDocs []ScrapedDocument
+	var skippedURLs []string
+	// docsMu guards scrapedDocs and skippedURLs, which colly's per-request
+	// callback goroutines can append to concurrently once Parallelism > 1.
+	var docsMu sync.Mutex
 
 	baseDomain := extractDomain(jobInput.URL)
 	allowedDomains := []string{
@@ -222,9 +384,24 @@ func setupCollector(ctx context.Context, jobInput JobInput, bucketName string, d
 		colly.AllowedDomains(allowedDomains...), // Allow both with and without www
 		colly.Debugger(&debug.LogDebugger{}),
 		colly.Async(true),
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/107.0.0.0 Safari/537.36"),
+		colly.UserAgent(defaultUserAgent),
+		colly.IgnoreRobotsTxt(), // we enforce robots.txt ourselves so we can also honor Crawl-delay
 	)
 
+	if err := c.Limit(&colly.LimitRule{
+		DomainGlob:  domainGlob(baseDomain),
+		Parallelism: parallelismFor(jobInput),
+		RandomDelay: time.Duration(jobInput.RandomDelayMs) * time.Millisecond,
+		Delay:       delayFor(jobInput),
+	}); err != nil {
+		log.Printf("Failed to set crawl rate limit: %v", err)
+	}
+
+	var robots *robotsCache
+	if respectRobotsTxt(jobInput) {
+		robots = newRobotsCache(defaultUserAgent)
+	}
+
 	// Add error handling
 	c.OnError(func(r *colly.Response, err error) {
 		log.Printf("Error scraping %s: %v", r.Request.URL, err)
@@ -232,66 +409,241 @@ func setupCollector(ctx context.Context, jobInput JobInput, bucketName string, d
 
 	// Log when starting a new page
 	c.OnRequest(func(r *colly.Request) {
+		if robots != nil && !robots.Allowed(r.URL) {
+			log.Printf("Skipping %s: disallowed by robots.txt", r.URL)
+			docsMu.Lock()
+			skippedURLs = append(skippedURLs, r.URL.String())
+			docsMu.Unlock()
+			// Abort() short-circuits before OnResponse/OnError, the only
+			// other places that mark a URL visited, so without this the
+			// frontier would re-enqueue and re-abort this URL on every
+			// future resume.
+			if err := visitQueue.MarkVisited(r.URL.String()); err != nil {
+				log.Printf("Error marking %s visited: %v", r.URL, err)
+			}
+			r.Abort()
+			return
+		}
+		setConditionalHeaders(r, manifest)
 		log.Printf("Visiting %s", r.URL.String())
 	})
 
+	extractors := newExtractorRegistry(jobInput.AllowedContentTypes)
+
 	// Handle all responses
 	c.OnResponse(func(r *colly.Response) {
-		handleResponse(r, bucketName, &scrapedDocs)
+		if err := visitQueue.MarkVisited(r.Request.URL.String()); err != nil {
+			log.Printf("Error marking %s visited: %v", r.Request.URL, err)
+		}
+		handleResponse(ctx, r, bucketName, extractors, manifest, jobInput.RenderMode, headless, &scrapedDocs, &docsMu)
+		flushVisitQueue(ctx, visitQueue)
 	})
 
-	// Handle HTML elements
-	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		handleHTML(e)
+	c.OnError(func(r *colly.Response, err error) {
+		if markErr := visitQueue.MarkVisited(r.Request.URL.String()); markErr != nil {
+			log.Printf("Error marking %s visited: %v", r.Request.URL, markErr)
+		}
+	})
+
+	// Handle HTML elements. In "sitemap"/"feed" mode, link discovery is
+	// skipped entirely in favor of the deterministic seed list.
+	if followsLinks(jobInput) {
+		c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+			handleHTML(e, visitQueue)
+		})
+	}
+
+	c.OnScraped(func(r *colly.Response) {
+		if crawlDelay := robotsCrawlDelay(robots, r.Request.URL); crawlDelay > 0 {
+			time.Sleep(crawlDelay)
+		}
 	})
 
-	return c, &scrapedDocs
+	return c, &scrapedDocs, &skippedURLs
+}
+
+// setConditionalHeaders adds If-None-Match/If-Modified-Since headers for
+// URLs the manifest already has an ETag or Last-Modified value for, so
+// unchanged pages can be answered with a cheap 304.
+func setConditionalHeaders(r *colly.Request, manifest *crawlManifest) {
+	entry, ok := manifest.lookup(r.URL.String())
+	if !ok {
+		return
+	}
+	if entry.ETag != "" {
+		r.Headers.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		r.Headers.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// flushVisitQueue snapshots the visit queue to GCS after each page so a
+// preempted job can resume with at most one page of lost progress.
+func flushVisitQueue(ctx context.Context, visitQueue VisitQueue) {
+	if err := visitQueue.Snapshot(ctx); err != nil {
+		log.Printf("Error snapshotting visit queue: %v", err)
+	}
+}
+
+// parallelismFor returns the configured per-host parallelism, defaulting to
+// colly's usual single-worker-per-domain behavior when unset.
+func parallelismFor(jobInput JobInput) int {
+	if jobInput.Parallelism > 0 {
+		return jobInput.Parallelism
+	}
+	return 1
+}
+
+// delayFor converts the configured per-host rate limit into the fixed delay
+// colly.LimitRule expects between requests to the same domain.
+func delayFor(jobInput JobInput) time.Duration {
+	if jobInput.RateLimitPerHost <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / jobInput.RateLimitPerHost)
+}
+
+// respectRobotsTxt reports whether robots.txt should be honored, defaulting
+// to true when JobInput doesn't specify.
+func respectRobotsTxt(jobInput JobInput) bool {
+	if jobInput.RespectRobotsTxt == nil {
+		return true
+	}
+	return *jobInput.RespectRobotsTxt
 }
 
-func handleResponse(r *colly.Response, bucketName string, scrapedDocs *[]ScrapedDocument) {
+// robotsCrawlDelay returns the robots.txt Crawl-delay for u, or 0 if robots
+// handling is disabled or the site advertised none.
+func robotsCrawlDelay(robots *robotsCache, u *url.URL) time.Duration {
+	if robots == nil {
+		return 0
+	}
+	return robots.CrawlDelay(u)
+}
+
+func handleResponse(ctx context.Context, r *colly.Response, bucketName string, extractors *extractorRegistry, manifest *crawlManifest, renderMode string, headless Fetcher, scrapedDocs *[]ScrapedDocument, docsMu *sync.Mutex) {
+	url := r.Request.URL.String()
 	contentType := r.Headers.Get("Content-Type")
-	log.Printf("Got response from %s (type: %s)", r.Request.URL, contentType)
+	log.Printf("Got response from %s (type: %s, status: %d)", url, contentType, r.StatusCode)
 
-	// Skip non-HTML, non-PDF content
-	if !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/pdf") {
-		log.Printf("Skipping non-HTML/PDF content type: %s", contentType)
+	if r.StatusCode == http.StatusNotModified {
+		log.Printf("%s is unchanged (304), skipping re-upload", url)
+		manifest.recordUnchanged(url)
 		return
 	}
 
-	// Generate filename from URL
-	filename := sanitizeFilename(r.Request.URL.String())
-	if strings.Contains(contentType, "application/pdf") {
-		if !strings.HasSuffix(filename, ".pdf") {
-			filename += ".pdf"
-		}
-	} else {
-		if !strings.HasSuffix(filename, ".html") {
-			filename += ".html"
-		}
+	extractor := extractors.find(contentType, url)
+	if extractor == nil {
+		log.Printf("Skipping unhandled content type: %s", contentType)
+		return
+	}
+
+	var screenshot []byte
+	if strings.Contains(contentType, "text/html") {
+		renderedHTML, shot := renderHTMLIfNeeded(ctx, url, string(r.Body), renderMode, headless)
+		r.Body = []byte(renderedHTML)
+		screenshot = shot
+	}
+
+	sha := sha256Hex(r.Body)
+	if previous, ok := manifest.lookup(url); ok && previous.SHA256 == sha {
+		log.Printf("%s is byte-identical to the previous crawl, skipping re-upload", url)
+		manifest.recordUnchanged(url)
+		return
 	}
 
+	// Generate filename from URL, preserving/adding the extension implied by
+	// the content type so downloaded files open correctly.
+	filename := sanitizeFilename(url)
+	filename = ensureExtension(filename, contentType)
+
 	// Create GCS path
 	gcsPath := fmt.Sprintf("gs://%s/%s", bucketName, filename)
 	log.Printf("Saving content to: %s", gcsPath)
 
-	// Write content to GCS
-	if err := writeDataToGCS(context.Background(), bucketName, filename, r.Body); err != nil {
+	// Write the raw content to GCS
+	if err := writeDataToGCS(ctx, bucketName, filename, r.Body); err != nil {
 		log.Printf("Error writing to GCS: %v", err)
 		return
 	}
 
-	// Add to scraped documents
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
 	doc := ScrapedDocument{
-		URL:         r.Request.URL.String(),
-		Filename:    filename,
-		GCSPath:     gcsPath,
-		ContentType: contentType,
+		URL:          url,
+		Filename:     filename,
+		GCSPath:      gcsPath,
+		ContentType:  contentType,
+		ETag:         r.Headers.Get("ETag"),
+		LastModified: r.Headers.Get("Last-Modified"),
+		SHA256:       sha,
+		FetchedAt:    fetchedAt,
+	}
+
+	// Write a normalized .txt/.md sidecar alongside the raw document so
+	// downstream RAG ingestion doesn't need to parse every format itself.
+	extracted, err := extractor.Extract(ctx, r)
+	if err != nil {
+		log.Printf("Error extracting content from %s: %v", r.Request.URL, err)
+	} else {
+		sidecarFilename := strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + extracted.SidecarExt
+		if err := writeDataToGCS(ctx, bucketName, sidecarFilename, []byte(extracted.Text)); err != nil {
+			log.Printf("Error writing extracted sidecar to GCS: %v", err)
+		} else {
+			doc.ExtractedGCSPath = fmt.Sprintf("gs://%s/%s", bucketName, sidecarFilename)
+		}
 	}
+
+	if len(screenshot) > 0 {
+		if err := writeDataToGCS(ctx, bucketName, screenshotFilename(filename), screenshot); err != nil {
+			log.Printf("Error writing screenshot to GCS: %v", err)
+		}
+	}
+
+	manifest.recordScraped(manifestEntry{
+		URL:          url,
+		ETag:         doc.ETag,
+		LastModified: doc.LastModified,
+		SHA256:       sha,
+		FetchedAt:    fetchedAt,
+	})
+
+	docsMu.Lock()
 	*scrapedDocs = append(*scrapedDocs, doc)
+	docsMu.Unlock()
 	log.Printf("Successfully saved document: %s", gcsPath)
 }
 
-func handleHTML(e *colly.HTMLElement) {
+// ensureExtension appends the file extension implied by contentType if
+// filename doesn't already have one, so downloaded files open correctly.
+func ensureExtension(filename, contentType string) string {
+	ext := contentTypeExtension(contentType)
+	if ext == "" || strings.HasSuffix(strings.ToLower(filename), "."+ext) {
+		return filename
+	}
+	return filename + "." + ext
+}
+
+var contentTypeExtensions = map[string]string{
+	"text/html":        "html",
+	"application/pdf":  "pdf",
+	"text/plain":       "txt",
+	"text/markdown":    "md",
+	"text/csv":         "csv",
+	"wordprocessingml": "docx",
+	"presentationml":   "pptx",
+}
+
+func contentTypeExtension(contentType string) string {
+	for substr, ext := range contentTypeExtensions {
+		if strings.Contains(contentType, substr) {
+			return ext
+		}
+	}
+	return ""
+}
+
+func handleHTML(e *colly.HTMLElement, visitQueue VisitQueue) {
 	link := e.Attr("href")
 	log.Printf("Found link: %s", link)
 
@@ -302,6 +654,12 @@ func handleHTML(e *colly.HTMLElement) {
 		return
 	}
 
+	// Record the link in the persistent frontier so it survives a restart,
+	// independent of whether colly's in-memory queue decides to visit it.
+	if err := visitQueue.Enqueue(absoluteURL, e.Request.Depth+1); err != nil {
+		log.Printf("Error enqueueing %s: %v", absoluteURL, err)
+	}
+
 	if strings.HasSuffix(strings.ToLower(link), ".pdf") {
 		log.Printf("Found PDF link: %s", absoluteURL)
 		e.Request.Visit(absoluteURL)
@@ -312,7 +670,7 @@ func handleHTML(e *colly.HTMLElement) {
 	}
 }
 
-func saveResults(ctx context.Context, firestoreClient *firestore.Client, docRef *firestore.DocumentRef, scrapedDocs *[]ScrapedDocument) {
+func saveResults(ctx context.Context, firestoreClient *firestore.Client, docRef *firestore.DocumentRef, scrapedDocs *[]ScrapedDocument, skippedURLs *[]string, manifest *crawlManifest) {
 	// Write results as JSON to stdout for job results
 	if err := json.NewEncoder(os.Stdout).Encode(scrapedDocs); err != nil {
 		updateJobError(ctx, docRef, fmt.Errorf("error encoding results: %v", err))
@@ -320,9 +678,16 @@ func saveResults(ctx context.Context, firestoreClient *firestore.Client, docRef
 		return
 	}
 
+	added, updated, unchanged, deleted := manifest.counts()
+
 	// Update the job document with results
 	resultData := map[string]interface{}{
 		"scraped_documents": scrapedDocs,
+		"skipped_urls":      skippedURLs,
+		"added":             added,
+		"updated":           updated,
+		"unchanged":         unchanged,
+		"deleted":           deleted,
 	}
 
 	_, err := docRef.Update(ctx, []firestore.Update{
@@ -406,9 +771,11 @@ func generateBucketName(projectID string, qEngineName string) (string, error) {
 	return bucketName, nil
 }
 
-// initializeBucket initializes the GCS bucket by creating it if it doesn't exist
-// or clearing its contents if it does
-func initializeBucket(ctx context.Context, projectID, bucketName string) error {
+// initializeBucket initializes the GCS bucket by creating it if it doesn't
+// exist. If fullRefresh is true and the bucket already exists, its contents
+// are cleared first; otherwise existing objects are left in place so the
+// crawl manifest can drive an incremental re-crawl.
+func initializeBucket(ctx context.Context, projectID, bucketName string, fullRefresh bool) error {
 	bucket := storageClient.Bucket(bucketName)
 
 	// Check if bucket exists
@@ -420,9 +787,9 @@ func initializeBucket(ctx context.Context, projectID, bucketName string) error {
 		}
 	} else if err != nil {
 		return fmt.Errorf("error checking bucket: %v", err)
-	} else {
-		// Bucket exists, clear all objects
-		log.Printf("Clearing existing objects from bucket %s", bucketName)
+	} else if fullRefresh {
+		// Bucket exists and a full refresh was requested, clear all objects
+		log.Printf("FullRefresh requested, clearing existing objects from bucket %s", bucketName)
 		it := bucket.Objects(ctx, nil)
 		for {
 			attrs, err := it.Next()
@@ -436,6 +803,8 @@ func initializeBucket(ctx context.Context, projectID, bucketName string) error {
 				return fmt.Errorf("error deleting object %s: %v", attrs.Name, err)
 			}
 		}
+	} else {
+		log.Printf("Bucket %s already exists, reusing for incremental crawl", bucketName)
 	}
 	return nil
 }
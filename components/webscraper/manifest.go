@@ -0,0 +1,175 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"cloud.google.com/go/storage"
+)
+
+const manifestObjectName = ".crawl-manifest.json"
+
+// manifestEntry records what was last seen for a single URL, so a later run
+// of the same EngineName can tell whether the page changed.
+type manifestEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	SHA256       string `json:"sha256"`
+	FetchedAt    string `json:"fetched_at"`
+}
+
+// crawlManifest is the per-engine record of every URL scraped on a previous
+// run, used to make re-crawls incremental: unchanged pages are skipped, and
+// pages no longer seen are reported as deleted.
+type crawlManifest struct {
+	bucketName string
+
+	mu      sync.Mutex
+	entries map[string]manifestEntry
+	seen    map[string]bool
+
+	added, updated, unchanged int
+}
+
+// loadCrawlManifest reads the previous manifest for bucketName, or returns
+// an empty one if none exists yet (e.g. the engine's first run).
+func loadCrawlManifest(ctx context.Context, bucketName string) (*crawlManifest, error) {
+	m := &crawlManifest{
+		bucketName: bucketName,
+		entries:    make(map[string]manifestEntry),
+		seen:       make(map[string]bool),
+	}
+
+	r, err := storageClient.Bucket(bucketName).Object(manifestObjectName).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %v", err)
+	}
+	defer r.Close()
+
+	var entries []manifestEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("error decoding manifest: %v", err)
+	}
+	for _, entry := range entries {
+		m.entries[entry.URL] = entry
+	}
+	return m, nil
+}
+
+// lookup returns the previous manifest entry for url, if any.
+func (m *crawlManifest) lookup(url string) (manifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[url]
+	return entry, ok
+}
+
+// recordUnchanged marks url as seen in this run without updating its entry,
+// since the body didn't change (a 304 response, or a matching SHA-256).
+func (m *crawlManifest) recordUnchanged(url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seen[url] = true
+	m.unchanged++
+}
+
+// recordScraped updates url's manifest entry after a successful (re)scrape,
+// tracking whether this is a new URL or a changed one.
+func (m *crawlManifest) recordScraped(entry manifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, existed := m.entries[entry.URL]; existed {
+		m.updated++
+	} else {
+		m.added++
+	}
+	m.entries[entry.URL] = entry
+	m.seen[entry.URL] = true
+}
+
+// deletedURLs returns every URL present in the previous manifest that wasn't
+// seen (scraped or confirmed unchanged) in this run.
+func (m *crawlManifest) deletedURLs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deletedURLsLocked()
+}
+
+// deletedURLsLocked is deletedURLs without acquiring m.mu, for callers that
+// already hold the lock.
+func (m *crawlManifest) deletedURLsLocked() []string {
+	var deleted []string
+	for url := range m.entries {
+		if !m.seen[url] {
+			deleted = append(deleted, url)
+		}
+	}
+	return deleted
+}
+
+// counts returns the added/updated/unchanged/deleted summary for the job
+// result.
+func (m *crawlManifest) counts() (added, updated, unchanged, deleted int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.added, m.updated, m.unchanged, len(m.deletedURLsLocked())
+}
+
+// save writes the manifest back to GCS, dropping any entries for URLs that
+// were deleted in this run.
+func (m *crawlManifest) save(ctx context.Context) error {
+	m.mu.Lock()
+	entries := make([]manifestEntry, 0, len(m.seen))
+	for url := range m.seen {
+		entries = append(entries, m.entries[url])
+	}
+	m.mu.Unlock()
+
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %v", err)
+	}
+
+	writer := storageClient.Bucket(m.bucketName).Object(manifestObjectName).NewWriter(ctx)
+	if _, err := writer.Write(body); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	return writer.Close()
+}
+
+// sha256Hex returns the lowercase hex SHA-256 digest of content.
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// logManifestSummary logs the added/updated/unchanged/deleted counts after a
+// crawl completes.
+func logManifestSummary(m *crawlManifest) {
+	added, updated, unchanged, deleted := m.counts()
+	log.Printf("Crawl manifest: %d added, %d updated, %d unchanged, %d deleted", added, updated, unchanged, deleted)
+}
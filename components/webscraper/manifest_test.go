@@ -0,0 +1,104 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestManifest(previous ...manifestEntry) *crawlManifest {
+	m := &crawlManifest{
+		entries: make(map[string]manifestEntry),
+		seen:    make(map[string]bool),
+	}
+	for _, entry := range previous {
+		m.entries[entry.URL] = entry
+	}
+	return m
+}
+
+func TestManifestRecordScrapedDistinguishesAddedFromUpdated(t *testing.T) {
+	m := newTestManifest(manifestEntry{URL: "https://example.com/existing", SHA256: "old"})
+
+	m.recordScraped(manifestEntry{URL: "https://example.com/existing", SHA256: "new"})
+	m.recordScraped(manifestEntry{URL: "https://example.com/new", SHA256: "new"})
+
+	added, updated, unchanged, deleted := m.counts()
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, 0, unchanged)
+	assert.Equal(t, 0, deleted)
+}
+
+func TestManifestRecordUnchangedIncrementsUnchanged(t *testing.T) {
+	m := newTestManifest(manifestEntry{URL: "https://example.com/a"})
+
+	m.recordUnchanged("https://example.com/a")
+
+	added, updated, unchanged, deleted := m.counts()
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 0, updated)
+	assert.Equal(t, 1, unchanged)
+	assert.Equal(t, 0, deleted)
+}
+
+func TestManifestDeletedURLsReportsUnseenEntries(t *testing.T) {
+	m := newTestManifest(
+		manifestEntry{URL: "https://example.com/kept"},
+		manifestEntry{URL: "https://example.com/gone"},
+	)
+	m.recordUnchanged("https://example.com/kept")
+
+	assert.Equal(t, []string{"https://example.com/gone"}, m.deletedURLs())
+
+	added, updated, unchanged, deleted := m.counts()
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 0, updated)
+	assert.Equal(t, 1, unchanged)
+	assert.Equal(t, 1, deleted)
+}
+
+func TestManifestLookupReturnsPreviousEntry(t *testing.T) {
+	m := newTestManifest(manifestEntry{URL: "https://example.com/a", ETag: `"v1"`})
+
+	entry, ok := m.lookup("https://example.com/a")
+	assert.True(t, ok)
+	assert.Equal(t, `"v1"`, entry.ETag)
+
+	_, ok = m.lookup("https://example.com/missing")
+	assert.False(t, ok)
+}
+
+// TestManifestCountsDoesNotDeadlock guards against counts() re-locking the
+// already-held mutex via deletedURLs(); it must return promptly rather than
+// hang.
+func TestManifestCountsDoesNotDeadlock(t *testing.T) {
+	m := newTestManifest(manifestEntry{URL: "https://example.com/gone"})
+
+	done := make(chan struct{})
+	go func() {
+		m.counts()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("counts() did not return; likely self-deadlocked on crawlManifest.mu")
+	}
+}
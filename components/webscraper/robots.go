@@ -0,0 +1,135 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// defaultUserAgent is the User-Agent the crawler presents to sites, and the
+// one robots.txt rules are matched against.
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/107.0.0.0 Safari/537.36"
+
+// robotsPolicy holds the parsed robots.txt group for a single host, plus the
+// host's advertised Crawl-delay so callers can throttle without re-fetching.
+type robotsPolicy struct {
+	group      *robotstxt.Group
+	crawlDelay time.Duration
+}
+
+// robotsCache fetches and caches robots.txt per host so it is only requested
+// once per crawl, and answers Allowed/CrawlDelay queries for the configured
+// User-Agent.
+type robotsCache struct {
+	userAgent string
+	client    *http.Client
+
+	mu       sync.Mutex
+	policies map[string]*robotsPolicy
+}
+
+func newRobotsCache(userAgent string) *robotsCache {
+	return &robotsCache{
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		policies:  make(map[string]*robotsPolicy),
+	}
+}
+
+// policyFor returns the cached robots.txt policy for u's host, fetching and
+// parsing it on first use. A missing or unfetchable robots.txt is treated as
+// "allow everything", matching standard crawler behavior.
+func (c *robotsCache) policyFor(u *url.URL) *robotsPolicy {
+	host := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	if p, ok := c.policies[host]; ok {
+		c.mu.Unlock()
+		return p
+	}
+	c.mu.Unlock()
+
+	p := c.fetch(host)
+
+	c.mu.Lock()
+	c.policies[host] = p
+	c.mu.Unlock()
+
+	return p
+}
+
+func (c *robotsCache) fetch(host string) *robotsPolicy {
+	robotsURL := host + "/robots.txt"
+
+	resp, err := c.client.Get(robotsURL)
+	if err != nil {
+		log.Printf("Could not fetch %s, allowing all: %v", robotsURL, err)
+		return allowAllPolicy()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		log.Printf("%s returned status %d, allowing all", robotsURL, resp.StatusCode)
+		return allowAllPolicy()
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Failed to read %s, allowing all: %v", robotsURL, err)
+		return allowAllPolicy()
+	}
+
+	data, err := robotstxt.FromBytes(body)
+	if err != nil {
+		log.Printf("Failed to parse %s, allowing all: %v", robotsURL, err)
+		return allowAllPolicy()
+	}
+
+	group := data.FindGroup(c.userAgent)
+	return &robotsPolicy{
+		group:      group,
+		crawlDelay: group.CrawlDelay,
+	}
+}
+
+func allowAllPolicy() *robotsPolicy {
+	data, _ := robotstxt.FromBytes(nil)
+	return &robotsPolicy{group: data.FindGroup("*")}
+}
+
+// Allowed reports whether u may be visited under this cache's User-Agent.
+func (c *robotsCache) Allowed(u *url.URL) bool {
+	return c.policyFor(u).group.Test(u.Path)
+}
+
+// CrawlDelay returns the Crawl-delay directive for u's host, or 0 if none was
+// advertised.
+func (c *robotsCache) CrawlDelay(u *url.URL) time.Duration {
+	return c.policyFor(u).crawlDelay
+}
+
+// domainGlob turns a host into the "*domain*" glob colly.Limit expects so the
+// rule applies to the host and its subdomains.
+func domainGlob(host string) string {
+	return fmt.Sprintf("*%s*", host)
+}
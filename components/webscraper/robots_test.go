@@ -0,0 +1,139 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRobotsCacheAllowedHonorsDisallow(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\nAllow: /private/public.html\n")
+	}))
+	defer ts.Close()
+
+	cache := newRobotsCache(defaultUserAgent)
+
+	allowedURL, _ := url.Parse(ts.URL + "/public")
+	assert.True(t, cache.Allowed(allowedURL))
+
+	disallowedURL, _ := url.Parse(ts.URL + "/private/secret")
+	assert.False(t, cache.Allowed(disallowedURL))
+
+	// A more specific Allow rule should win over a blanket Disallow.
+	overriddenURL, _ := url.Parse(ts.URL + "/private/public.html")
+	assert.True(t, cache.Allowed(overriddenURL))
+}
+
+func TestRobotsCacheAllowsAllWhenRobotsTxtMissing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	cache := newRobotsCache(defaultUserAgent)
+	u, _ := url.Parse(ts.URL + "/anything")
+	assert.True(t, cache.Allowed(u), "a missing/unfetchable robots.txt should allow everything")
+}
+
+func TestRobotsCacheCrawlDelay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 2\n")
+	}))
+	defer ts.Close()
+
+	cache := newRobotsCache(defaultUserAgent)
+	u, _ := url.Parse(ts.URL + "/page")
+	assert.Equal(t, 2*time.Second, cache.CrawlDelay(u))
+}
+
+func TestRobotsCacheCachesPerHost(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "User-agent: *\nDisallow:\n")
+	}))
+	defer ts.Close()
+
+	cache := newRobotsCache(defaultUserAgent)
+	u, _ := url.Parse(ts.URL + "/a")
+	cache.Allowed(u)
+	cache.Allowed(u)
+	cache.CrawlDelay(u)
+
+	assert.Equal(t, 1, requests, "robots.txt should only be fetched once per host")
+}
+
+func TestDomainGlob(t *testing.T) {
+	assert.Equal(t, "*example.com*", domainGlob("example.com"))
+}
+
+func TestParallelismForDefaultsToOne(t *testing.T) {
+	assert.Equal(t, 1, parallelismFor(JobInput{}))
+	assert.Equal(t, 5, parallelismFor(JobInput{Parallelism: 5}))
+}
+
+func TestDelayForConvertsRatePerHostToDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), delayFor(JobInput{}))
+	assert.Equal(t, 500*time.Millisecond, delayFor(JobInput{RateLimitPerHost: 2}))
+}
+
+func TestRespectRobotsTxtDefaultsToTrue(t *testing.T) {
+	assert.True(t, respectRobotsTxt(JobInput{}))
+
+	disabled := false
+	assert.False(t, respectRobotsTxt(JobInput{RespectRobotsTxt: &disabled}))
+
+	enabled := true
+	assert.True(t, respectRobotsTxt(JobInput{RespectRobotsTxt: &enabled}))
+}
+
+// TestRobotsAbortMarksVisitQueueVisited exercises the real OnRequest abort
+// path through setupCollector: a URL disallowed by robots.txt must be marked
+// visited in the VisitQueue, not just skipped, otherwise it is re-enqueued
+// and re-aborted forever on every resume of the same job.
+func TestRobotsAbortMarksVisitQueueVisited(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>secret</body></html>")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	jobInput := JobInput{URL: ts.URL, DepthLimit: "1"}
+	visitQueue := newTestVisitQueue()
+	manifest := newTestManifest()
+
+	collector, _, skippedURLs := setupCollector(context.Background(), jobInput, "test-bucket", nil, visitQueue, manifest, nil)
+
+	disallowedURL := ts.URL + "/private"
+	assert.NoError(t, collector.Visit(disallowedURL))
+	collector.Wait()
+
+	assert.Contains(t, *skippedURLs, disallowedURL)
+	assert.True(t, visitQueue.visited[disallowedURL], "a robots-disallowed URL must be marked visited so it stops being re-enqueued")
+}
@@ -0,0 +1,257 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Seed modes selectable via JobInput.SeedMode.
+const (
+	seedModeCrawl   = "crawl"
+	seedModeSitemap = "sitemap"
+	seedModeFeed    = "feed"
+	seedModeHybrid  = "hybrid"
+)
+
+// normalizeSeedMode defaults an empty SeedMode to the previous link-following
+// behavior, so existing jobs are unaffected.
+func normalizeSeedMode(mode string) string {
+	if mode == "" {
+		return seedModeCrawl
+	}
+	return mode
+}
+
+// sitemapURLSet and sitemapIndex model the two possible root elements of
+// sitemap.xml: a set of page URLs, or an index of other sitemaps.
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name        `xml:"sitemapindex"`
+	Sitemaps []sitemapIdxRef `xml:"sitemap"`
+}
+
+type sitemapIdxRef struct {
+	Loc string `xml:"loc"`
+}
+
+// rssFeed and atomFeed model the subset of RSS 2.0 / Atom 1.0 needed to pull
+// out entry links.
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Items []struct {
+			Link string `xml:"link"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name `xml:"feed"`
+	Entries []struct {
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+var seedHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchSitemapURLs fetches sitemapURL and recursively expands sitemap index
+// files and .gz-compressed sitemaps, returning every page URL it finds.
+func fetchSitemapURLs(sitemapURL string) ([]string, error) {
+	body, err := fetchSeedDocument(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var index sitemapIndex
+	if err := xml.Unmarshal(body, &index); err == nil && len(index.Sitemaps) > 0 {
+		var urls []string
+		for _, ref := range index.Sitemaps {
+			log.Printf("Expanding sitemap index entry: %s", ref.Loc)
+			childURLs, err := fetchSitemapURLs(ref.Loc)
+			if err != nil {
+				log.Printf("Error expanding sitemap %s: %v", ref.Loc, err)
+				continue
+			}
+			urls = append(urls, childURLs...)
+		}
+		return urls, nil
+	}
+
+	var urlSet sitemapURLSet
+	if err := xml.Unmarshal(body, &urlSet); err != nil {
+		return nil, fmt.Errorf("error parsing sitemap %s: %v", sitemapURL, err)
+	}
+
+	urls := make([]string, 0, len(urlSet.URLs))
+	for _, u := range urlSet.URLs {
+		if u.Loc != "" {
+			urls = append(urls, u.Loc)
+		}
+	}
+	return urls, nil
+}
+
+// fetchFeedURLs fetches and parses an RSS 2.0 or Atom 1.0 feed, returning
+// each entry's link.
+func fetchFeedURLs(feedURL string) ([]string, error) {
+	body, err := fetchSeedDocument(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var rss rssFeed
+	if err := xml.Unmarshal(body, &rss); err == nil && len(rss.Channel.Items) > 0 {
+		urls := make([]string, 0, len(rss.Channel.Items))
+		for _, item := range rss.Channel.Items {
+			if item.Link != "" {
+				urls = append(urls, item.Link)
+			}
+		}
+		return urls, nil
+	}
+
+	var atom atomFeed
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		return nil, fmt.Errorf("error parsing feed %s: %v", feedURL, err)
+	}
+
+	var urls []string
+	for _, entry := range atom.Entries {
+		urls = append(urls, atomEntryLink(entry.Links))
+	}
+	return nonEmpty(urls), nil
+}
+
+func atomEntryLink(links []struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+func nonEmpty(urls []string) []string {
+	out := urls[:0]
+	for _, u := range urls {
+		if u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// fetchSeedDocument fetches url and transparently gunzips the body if it is
+// .gz-compressed, per the sitemaps.org spec for compressed sitemaps.
+func fetchSeedDocument(url string) ([]byte, error) {
+	resp, err := seedHTTPClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("fetching %s returned status %d", url, resp.StatusCode)
+	}
+
+	var reader io.Reader = resp.Body
+	if strings.HasSuffix(strings.ToLower(url), ".gz") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error gunzipping %s: %v", url, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	return io.ReadAll(reader)
+}
+
+// seedURLs returns the list of pages to enqueue up front for the given
+// JobInput.SeedMode.
+func seedURLs(jobInput JobInput) ([]string, error) {
+	switch normalizeSeedMode(jobInput.SeedMode) {
+	case seedModeSitemap, seedModeHybrid:
+		return fetchSitemapURLs(resolveSitemapURL(jobInput))
+	case seedModeFeed:
+		return fetchFeedURLs(jobInput.FeedURL)
+	default:
+		return nil, nil
+	}
+}
+
+// resolveSitemapURL returns JobInput.SitemapURL if the job specified one,
+// otherwise checks the site's robots.txt for a "Sitemap:" directive, and
+// finally falls back to the conventional /sitemap.xml path.
+func resolveSitemapURL(jobInput JobInput) string {
+	if jobInput.SitemapURL != "" {
+		return jobInput.SitemapURL
+	}
+
+	baseURL := strings.TrimSuffix(jobInput.URL, "/")
+	if robotsSitemap := findRobotsSitemapDirective(baseURL + "/robots.txt"); robotsSitemap != "" {
+		return robotsSitemap
+	}
+	return baseURL + "/sitemap.xml"
+}
+
+// findRobotsSitemapDirective returns the URL from the first "Sitemap:" line
+// in robots.txt, or "" if there isn't one.
+func findRobotsSitemapDirective(robotsURL string) string {
+	body, err := fetchSeedDocument(robotsURL)
+	if err != nil {
+		log.Printf("Could not fetch %s to look for a Sitemap directive: %v", robotsURL, err)
+		return ""
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToLower(line), "sitemap:") {
+			return strings.TrimSpace(line[len("sitemap:"):])
+		}
+	}
+	return ""
+}
+
+// followsLinks reports whether the collector should still crawl discovered
+// links (up to DepthLimit) in addition to any seeded URLs.
+func followsLinks(jobInput JobInput) bool {
+	mode := normalizeSeedMode(jobInput.SeedMode)
+	return mode == seedModeCrawl || mode == seedModeHybrid
+}
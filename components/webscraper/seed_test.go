@@ -0,0 +1,130 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSeedMode(t *testing.T) {
+	assert.Equal(t, seedModeCrawl, normalizeSeedMode(""))
+	assert.Equal(t, seedModeSitemap, normalizeSeedMode(seedModeSitemap))
+}
+
+func TestFollowsLinks(t *testing.T) {
+	assert.True(t, followsLinks(JobInput{SeedMode: ""}))
+	assert.True(t, followsLinks(JobInput{SeedMode: seedModeCrawl}))
+	assert.True(t, followsLinks(JobInput{SeedMode: seedModeHybrid}))
+	assert.False(t, followsLinks(JobInput{SeedMode: seedModeSitemap}))
+	assert.False(t, followsLinks(JobInput{SeedMode: seedModeFeed}))
+}
+
+func TestFetchSitemapURLsParsesURLSet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<urlset><url><loc>https://example.com/a</loc></url><url><loc>https://example.com/b</loc></url></urlset>`))
+	}))
+	defer ts.Close()
+
+	urls, err := fetchSitemapURLs(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, urls)
+}
+
+func TestFetchSitemapURLsExpandsIndex(t *testing.T) {
+	var childURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sitemap-index.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<sitemapindex><sitemap><loc>` + childURL + `</loc></sitemap></sitemapindex>`))
+	})
+	mux.HandleFunc("/child.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<urlset><url><loc>https://example.com/child-page</loc></url></urlset>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	childURL = ts.URL + "/child.xml"
+
+	urls, err := fetchSitemapURLs(ts.URL + "/sitemap-index.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/child-page"}, urls)
+}
+
+func TestFetchSitemapURLsGunzipsCompressedSitemap(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`<urlset><url><loc>https://example.com/zipped</loc></url></urlset>`))
+	gz.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	urls, err := fetchSitemapURLs(ts.URL + "/sitemap.xml.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/zipped"}, urls)
+}
+
+func TestFetchFeedURLsParsesRSS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss><channel><item><link>https://example.com/post1</link></item><item><link>https://example.com/post2</link></item></channel></rss>`))
+	}))
+	defer ts.Close()
+
+	urls, err := fetchFeedURLs(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/post1", "https://example.com/post2"}, urls)
+}
+
+func TestFetchFeedURLsParsesAtom(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<feed><entry><link rel="alternate" href="https://example.com/entry1"/></entry></feed>`))
+	}))
+	defer ts.Close()
+
+	urls, err := fetchFeedURLs(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/entry1"}, urls)
+}
+
+func TestResolveSitemapURLPrefersJobInputOverride(t *testing.T) {
+	jobInput := JobInput{URL: "https://example.com", SitemapURL: "https://example.com/custom-sitemap.xml"}
+	assert.Equal(t, "https://example.com/custom-sitemap.xml", resolveSitemapURL(jobInput))
+}
+
+func TestResolveSitemapURLFallsBackToConventionalPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	jobInput := JobInput{URL: ts.URL}
+	assert.Equal(t, ts.URL+"/sitemap.xml", resolveSitemapURL(jobInput))
+}
+
+func TestFindRobotsSitemapDirective(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\nSitemap: https://example.com/from-robots.xml\n"))
+	}))
+	defer ts.Close()
+
+	assert.Equal(t, "https://example.com/from-robots.xml", findRobotsSitemapDirective(ts.URL+"/robots.txt"))
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"cloud.google.com/go/storage"
+)
+
+const (
+	frontierObjectName = "crawl-state/frontier.ndjson"
+	visitedObjectName  = "crawl-state/visited.txt"
+)
+
+// frontierEntry is a single line of the append-only frontier NDJSON file.
+type frontierEntry struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// VisitQueue is a persistent queue of URLs to crawl, used so a job that is
+// preempted mid-crawl can resume from where it left off instead of
+// restarting from the seed URL.
+type VisitQueue interface {
+	// Enqueue records url (at the given depth) as pending, unless it has
+	// already been visited or is already queued.
+	Enqueue(url string, depth int) error
+	// Dequeue returns the next pending URL and its depth, and false if the
+	// queue is empty.
+	Dequeue() (url string, depth int, ok bool)
+	// MarkVisited records url as visited so it will not be re-enqueued.
+	MarkVisited(url string) error
+	// Snapshot flushes any buffered state to persistent storage.
+	Snapshot(ctx context.Context) error
+}
+
+// gcsVisitQueue is a VisitQueue backed by objects in the job's GCS bucket: an
+// append-only NDJSON frontier and a newline-delimited visited-URL file used
+// for dedupe. It mirrors the on-disk frontier files long-running crawlers
+// traditionally use, just with GCS objects standing in for local disk.
+type gcsVisitQueue struct {
+	ctx        context.Context
+	bucketName string
+
+	mu      sync.Mutex
+	pending []frontierEntry
+	queued  map[string]bool
+	visited map[string]bool
+
+	// newFrontierLines and newVisitedLines accumulate entries added since the
+	// last Snapshot so flushing adds only those lines after each GCS
+	// object's existing contents (appendToGCS still rewrites the whole
+	// object, since GCS has no native append).
+	newFrontierLines []frontierEntry
+	newVisitedLines  []string
+
+	// flushMu serializes Snapshot calls. colly's Async collector runs each
+	// request's OnResponse callback in its own goroutine, so without this,
+	// concurrent calls to appendToGCS would each read the same "existing"
+	// object contents and the loser's write would silently clobber the
+	// winner's, dropping frontier/visited entries.
+	flushMu sync.Mutex
+}
+
+// newGCSVisitQueue loads any existing frontier/visited snapshot for
+// bucketName from GCS so a job restarted with the same JOB_ID resumes from
+// where it left off. If no snapshot exists, it returns an empty queue.
+func newGCSVisitQueue(ctx context.Context, bucketName string) (*gcsVisitQueue, error) {
+	q := &gcsVisitQueue{
+		ctx:        ctx,
+		bucketName: bucketName,
+		queued:     make(map[string]bool),
+		visited:    make(map[string]bool),
+	}
+
+	if err := q.loadVisited(); err != nil {
+		return nil, fmt.Errorf("error loading visited snapshot: %v", err)
+	}
+	if err := q.loadFrontier(); err != nil {
+		return nil, fmt.Errorf("error loading frontier snapshot: %v", err)
+	}
+
+	log.Printf("Resumed visit queue: %d pending, %d already visited", len(q.pending), len(q.visited))
+	return q, nil
+}
+
+func (q *gcsVisitQueue) loadVisited() error {
+	r, err := storageClient.Bucket(q.bucketName).Object(visitedObjectName).NewReader(q.ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			q.visited[line] = true
+		}
+	}
+	return scanner.Err()
+}
+
+func (q *gcsVisitQueue) loadFrontier() error {
+	r, err := storageClient.Bucket(q.bucketName).Object(frontierObjectName).NewReader(q.ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var entry frontierEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("Skipping malformed frontier line: %v", err)
+			continue
+		}
+		if q.visited[entry.URL] || q.queued[entry.URL] {
+			continue
+		}
+		q.queued[entry.URL] = true
+		q.pending = append(q.pending, entry)
+	}
+	return scanner.Err()
+}
+
+func (q *gcsVisitQueue) Enqueue(url string, depth int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.visited[url] || q.queued[url] {
+		return nil
+	}
+	entry := frontierEntry{URL: url, Depth: depth}
+	q.queued[url] = true
+	q.pending = append(q.pending, entry)
+	q.newFrontierLines = append(q.newFrontierLines, entry)
+	return nil
+}
+
+func (q *gcsVisitQueue) Dequeue() (string, int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return "", 0, false
+	}
+	entry := q.pending[0]
+	q.pending = q.pending[1:]
+	return entry.URL, entry.Depth, true
+}
+
+func (q *gcsVisitQueue) MarkVisited(url string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.visited[url] {
+		return nil
+	}
+	q.visited[url] = true
+	q.newVisitedLines = append(q.newVisitedLines, url)
+	return nil
+}
+
+// Snapshot appends any newly-enqueued or newly-visited URLs to the GCS
+// frontier and visited objects. It is safe to call concurrently, including
+// from colly's per-request callback goroutines: flushMu serializes the
+// actual GCS read-modify-write so concurrent flushes can't race.
+func (q *gcsVisitQueue) Snapshot(ctx context.Context) error {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	q.mu.Lock()
+	frontierLines := q.newFrontierLines
+	visitedLines := q.newVisitedLines
+	q.newFrontierLines = nil
+	q.newVisitedLines = nil
+	q.mu.Unlock()
+
+	if len(frontierLines) > 0 {
+		var buf []byte
+		for _, entry := range frontierLines {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("error marshaling frontier entry: %v", err)
+			}
+			buf = append(buf, line...)
+			buf = append(buf, '\n')
+		}
+		if err := appendToGCS(ctx, q.bucketName, frontierObjectName, buf); err != nil {
+			return fmt.Errorf("error appending to frontier: %v", err)
+		}
+	}
+
+	if len(visitedLines) > 0 {
+		var buf []byte
+		for _, url := range visitedLines {
+			buf = append(buf, []byte(url)...)
+			buf = append(buf, '\n')
+		}
+		if err := appendToGCS(ctx, q.bucketName, visitedObjectName, buf); err != nil {
+			return fmt.Errorf("error appending to visited file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// appendToGCS appends data to the named object, reading its current contents
+// first since GCS objects have no native append operation.
+func appendToGCS(ctx context.Context, bucketName, objectName string, data []byte) error {
+	bucket := storageClient.Bucket(bucketName)
+	obj := bucket.Object(objectName)
+
+	existing, err := readObjectIfExists(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	writer := obj.NewWriter(ctx)
+	if _, err := writer.Write(existing); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func readObjectIfExists(ctx context.Context, obj *storage.ObjectHandle) ([]byte, error) {
+	r, err := obj.NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf []byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		buf = append(buf, scanner.Bytes()...)
+		buf = append(buf, '\n')
+	}
+	return buf, scanner.Err()
+}